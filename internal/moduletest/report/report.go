@@ -0,0 +1,273 @@
+// Package report implements serialization of moduletest results into
+// machine-readable formats that external tooling, such as CI systems, can
+// consume.
+//
+// Two formats are currently supported: a line-delimited JSON event stream
+// that can be written incrementally as a test run progresses, and a JUnit
+// XML report that summarizes a completed run. Both are derived from the
+// same underlying [moduletest.ScenarioResult] and [moduletest.StepResult]
+// values that the rest of Terraform already produces, so adding a new
+// consumer should not require any changes to the test runner itself.
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/hashicorp/terraform/internal/checks"
+	"github.com/hashicorp/terraform/internal/moduletest"
+	"github.com/hashicorp/terraform/internal/tfdiags"
+)
+
+// EventWriter writes a stream of line-delimited JSON events describing a
+// test run as it progresses. Each call to WriteStep or WriteScenario emits
+// exactly one JSON object followed by a newline, so that a consumer can
+// begin processing results before the run has finished.
+type EventWriter struct {
+	w io.Writer
+}
+
+// NewEventWriter returns an EventWriter that writes its event stream to w.
+func NewEventWriter(w io.Writer) *EventWriter {
+	return &EventWriter{w: w}
+}
+
+// stepEvent is the public, stable schema for a single step result as
+// reported in the JSON event stream. Field names and meanings are part of
+// the public contract for consumers of `terraform test -json`-style output
+// and should not change without a corresponding schema version bump.
+type stepEvent struct {
+	Type     string `json:"type"` // always "step_complete"
+	Scenario string `json:"scenario"`
+	Step     string `json:"step"`
+	Status   string `json:"status"`
+	Implied  bool   `json:"implied"`
+
+	// ExpectedFailures mirrors [moduletest.StepResult.ExpectedFailures],
+	// carrying both the checkable address the test author listed and the
+	// status that check actually reported, so a consumer can tell an
+	// expected failure apart from an unexpected pass.
+	ExpectedFailures []checkResultEvent `json:"expected_failures,omitempty"`
+
+	// Postconditions mirrors [moduletest.StepResult.Postconditions]; it's
+	// omitted entirely if the step declared none.
+	Postconditions *checkResultEvent `json:"postconditions,omitempty"`
+
+	// Checks mirrors [moduletest.StepResult.Checks], one entry per checkable
+	// object that the step's plan or apply evaluated.
+	Checks []checkResultEvent `json:"checks,omitempty"`
+
+	Diagnostics []diagnosticEvent `json:"diagnostics,omitempty"`
+}
+
+// checkResultEvent is the public schema for a single checkable object's
+// result, used for Postconditions, Checks, and ExpectedFailures alike.
+type checkResultEvent struct {
+	Address         string   `json:"address"`
+	Status          string   `json:"status"`
+	FailureMessages []string `json:"failure_messages,omitempty"`
+}
+
+// scenarioEvent is the public schema for a scenario_complete event, emitted
+// once all of a scenario's steps have finished running.
+type scenarioEvent struct {
+	Type      string `json:"type"` // always "scenario_complete"
+	Scenario  string `json:"scenario"`
+	Status    string `json:"status"`
+	StepCount int    `json:"step_count"`
+}
+
+type diagnosticEvent struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// WriteStep emits a single step_complete event for the given scenario name
+// and step result.
+func (ew *EventWriter) WriteStep(scenarioName string, step moduletest.StepResult) error {
+	ev := stepEvent{
+		Type:     "step_complete",
+		Scenario: scenarioName,
+		Step:     step.Name,
+		Status:   step.Status.String(),
+		Implied:  step.IsImplied(),
+	}
+
+	for _, elem := range step.ExpectedFailures.Elems {
+		ev.ExpectedFailures = append(ev.ExpectedFailures, checkResultEvent{
+			Address: elem.Key.String(),
+			Status:  elem.Value.String(),
+		})
+	}
+
+	if step.Postconditions != nil {
+		ev.Postconditions = &checkResultEvent{
+			Status:          step.Postconditions.Status.String(),
+			FailureMessages: step.Postconditions.FailureMessages,
+		}
+	}
+
+	if step.Checks != nil {
+		for _, configResult := range step.Checks.ConfigResults.Elems {
+			for _, objResult := range configResult.Value.ObjectResults.Elems {
+				ev.Checks = append(ev.Checks, checkResultEvent{
+					Address:         objResult.Key.String(),
+					Status:          objResult.Value.Status.String(),
+					FailureMessages: objResult.Value.FailureMessages,
+				})
+			}
+		}
+	}
+
+	for _, d := range step.Diagnostics {
+		desc := d.Description()
+		ev.Diagnostics = append(ev.Diagnostics, diagnosticEvent{
+			Severity: d.Severity().String(),
+			Summary:  desc.Summary,
+			Detail:   desc.Detail,
+		})
+	}
+
+	return ew.encode(ev)
+}
+
+// WriteScenario emits a single scenario_complete event summarizing the
+// overall outcome of a scenario, once all of its steps have run.
+func (ew *EventWriter) WriteScenario(scenario moduletest.ScenarioResult) error {
+	ev := scenarioEvent{
+		Type:      "scenario_complete",
+		Scenario:  scenario.Name,
+		Status:    scenario.Status.String(),
+		StepCount: len(scenario.Steps),
+	}
+	return ew.encode(ev)
+}
+
+func (ew *EventWriter) encode(v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encoding test event: %w", err)
+	}
+	if _, err := ew.w.Write(raw); err != nil {
+		return err
+	}
+	_, err = io.WriteString(ew.w, "\n")
+	return err
+}
+
+// JUnitReport is a JUnit-XML-compatible representation of a full test run,
+// with one <testsuite> per scenario and one <testcase> per step.
+type JUnitReport struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name    string        `xml:"name,attr"`
+	Failure *junitMessage `xml:"failure,omitempty"`
+	Skipped *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// BuildJUnitReport assembles a JUnitReport from a completed set of scenario
+// results. Scenarios are reported in the order given, so callers that care
+// about stable output should sort their results before calling this.
+func BuildJUnitReport(scenarios []moduletest.ScenarioResult) *JUnitReport {
+	report := &JUnitReport{}
+	for _, scenario := range scenarios {
+		suite := junitTestSuite{
+			Name:  scenario.Name,
+			Tests: len(scenario.Steps),
+		}
+		for _, step := range scenario.Steps {
+			tc := junitTestCase{Name: step.Name}
+			switch step.Status {
+			case checks.StatusFail, checks.StatusError:
+				// JUnit's <error> element is conventionally reserved for
+				// failures in the test harness itself, as distinct from a
+				// failing assertion; Terraform doesn't make that
+				// distinction, so both map to <failure> as specified.
+				suite.Failures++
+				tc.Failure = failureMessage(step)
+			case checks.StatusUnknown:
+				suite.Skipped++
+				tc.Skipped = &junitMessage{Message: "step did not run to completion"}
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+		report.Suites = append(report.Suites, suite)
+	}
+	return report
+}
+
+func diagnosticsToJUnitMessage(diags tfdiags.Diagnostics) *junitMessage {
+	if len(diags) == 0 {
+		return &junitMessage{Message: "step failed"}
+	}
+	desc := diags[0].Description()
+	return &junitMessage{Message: desc.Summary, Body: desc.Detail}
+}
+
+// failureMessage builds the <failure> body for a step that failed, folding
+// in any mismatched snapshot diffs alongside the usual diagnostics so that a
+// snapshot-only failure (one with no diagnostics of its own) still reports
+// something useful.
+func failureMessage(step moduletest.StepResult) *junitMessage {
+	msg := diagnosticsToJUnitMessage(step.Diagnostics)
+
+	names := make([]string, 0, len(step.Snapshots))
+	for name, result := range step.Snapshots {
+		switch result.Status {
+		case moduletest.SnapshotFail, moduletest.SnapshotMissing:
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var diffs string
+	for _, name := range names {
+		diffs += fmt.Sprintf("snapshot %q mismatched:\n%s\n", name, step.Snapshots[name].Diff)
+	}
+	if diffs == "" {
+		return msg
+	}
+	if len(step.Diagnostics) == 0 {
+		msg.Message = "snapshot mismatch"
+	}
+	if msg.Body != "" {
+		msg.Body += "\n\n"
+	}
+	msg.Body += diffs
+	return msg
+}
+
+// Write renders the JUnit report as XML, with the standard XML declaration
+// prepended, to w.
+func (r *JUnitReport) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("encoding JUnit report: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
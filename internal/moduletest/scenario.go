@@ -1,8 +1,12 @@
 package moduletest
 
 import (
+	"regexp"
+	"time"
+
 	"github.com/hashicorp/terraform/internal/addrs"
 	"github.com/hashicorp/terraform/internal/checks"
+	"github.com/hashicorp/terraform/internal/plans"
 	"github.com/hashicorp/terraform/internal/states"
 	"github.com/hashicorp/terraform/internal/tfdiags"
 )
@@ -38,7 +42,11 @@ type StepResult struct {
 	// This field also takes into account field [ExpectedFailures]: a failure
 	// that was expected is counted as if it were passing, and any passing
 	// object is treated as a failure, thereby essentially inverting the
-	// result of those checks when considered in aggregate.
+	// result of those checks when considered in aggregate. Similarly, field
+	// [ExpectedDiagnostics] removes matched diagnostics from consideration,
+	// but promotes the step to [checks.StatusFail] if any expectation went
+	// unsatisfied, and field [Snapshots] promotes the step to
+	// [checks.StatusFail] if [Snapshots.AnyMismatch] is true.
 	//
 	// Status unknown represents that the step didn't run to completion but that
 	// any partial execution didn't encounter any failures or errors. For
@@ -83,6 +91,19 @@ type StepResult struct {
 	// [checks.StatusPass] then the overall step status is [checks.StatusFail].
 	ExpectedFailures addrs.Map[addrs.Checkable, checks.Status]
 
+	// ExpectedDiagnostics records the outcome of matching field Diagnostics
+	// against any "expect_diagnostics" blocks the test author declared for
+	// this step.
+	//
+	// A diagnostic that matches one of these expectations is removed from
+	// the set considered by [StepResult.Status] when deciding whether the
+	// step errored, in the same way that [ExpectedFailures] removes an
+	// expected check failure from consideration. If any entry in this slice
+	// has Satisfied false then the overall step status is promoted to
+	// [checks.StatusFail], even if every diagnostic that did appear was
+	// otherwise matched or the step would otherwise have passed.
+	ExpectedDiagnostics []ExpectedDiagnosticResult
+
 	// Diagnostics reports any diagnostics generated during this step.
 	//
 	// Diagnostics cannot be unambigously associated with specific checks, so
@@ -90,7 +111,284 @@ type StepResult struct {
 	// having status error, while in other cases the diagnostics may be totally
 	// unrelated to any of the checks and instead describe a more general
 	// problem.
+	//
+	// Diagnostics that were matched by an entry in [ExpectedDiagnostics] are
+	// still included here, so that UI code has the full picture if it wants
+	// it; it's the Status field that treats them as accounted for.
 	Diagnostics tfdiags.Diagnostics
+
+	// StartTime is when the runner began executing this step, and Duration
+	// is how long the step took to run to completion (or to whatever partial
+	// state it reached before being aborted). Both are zero for a step that
+	// never started, such as one left at [checks.StatusUnknown] because an
+	// earlier step in the same scenario failed first.
+	StartTime time.Time
+	Duration  time.Duration
+
+	// ResourceChanges summarizes the planned (and, for steps that applied
+	// their plan, applied) resource changes that were part of this step, for
+	// reporters that want to show something like "5 to add, 0 to change, 0
+	// to destroy" without needing to inspect the step's own plan directly.
+	//
+	// ResourceChanges is nil for implied steps that don't run a plan at all,
+	// such as some forms of cleanup step.
+	ResourceChanges *ResourceChangeSummary
+
+	// Snapshots describes the outcome of comparing each of the step's
+	// "snapshot" blocks, if any, against their golden files on disk.
+	//
+	// A mismatch here (see [Snapshots.AnyMismatch]) is folded into Status
+	// the same way a failing check is: it takes the step's status down to
+	// [checks.StatusFail] unless the run was in --update-snapshots mode, in
+	// which case the golden file was rewritten instead and the step is
+	// allowed to pass.
+	Snapshots Snapshots
+}
+
+// ResourceChangeSummary is a per-step count of planned resource changes,
+// grouped by the [plans.Action] that would be taken, along with enough
+// detail about each individual change for a reporter to list them if asked.
+type ResourceChangeSummary struct {
+	// ActionCounts gives the total number of resource instances planned for
+	// each action that appeared in the step's plan. Actions that didn't
+	// occur at all are omitted rather than present with a count of zero.
+	ActionCounts map[plans.Action]int
+
+	// Changes optionally lists each individual planned change, for
+	// reporters that want per-address detail rather than just the
+	// aggregate counts in ActionCounts. This is nil when only the
+	// aggregate counts were retained.
+	Changes []ResourceChange
+}
+
+// ResourceChange describes a single planned (or applied) change to a
+// resource instance, as captured from the plan that ran for a test step.
+type ResourceChange struct {
+	Address addrs.AbsResourceInstance
+	Action  plans.Action
+}
+
+// StepTimer captures the wall-clock start time of a test step so that the
+// runner can stamp StartTime and Duration onto the resulting [StepResult]
+// without threading its own clock-handling code through step execution.
+//
+// Typical use is to call [StartStepTimer] immediately before running a
+// step's plan/apply, then call [StepTimer.Stop] on the resulting
+// StepResult once the step has finished, whether it succeeded or not.
+//
+// NOTE: like [Scenario.Parallel], nothing in this package calls
+// StartStepTimer, StepTimer.Stop, or [BuildResourceChangeSummary] itself;
+// the step-execution code that runs a scenario's plans is a command/backend
+// concern that doesn't exist in this package, so wiring these in is left to
+// whatever calls into moduletest to actually run a step.
+type StepTimer struct {
+	start time.Time
+}
+
+// StartStepTimer begins timing a test step.
+func StartStepTimer() StepTimer {
+	return StepTimer{start: time.Now()}
+}
+
+// Stop stamps sr.StartTime and sr.Duration based on when t was started and
+// how much time has elapsed since.
+func (t StepTimer) Stop(sr *StepResult) {
+	sr.StartTime = t.start
+	sr.Duration = time.Since(t.start)
+}
+
+// BuildResourceChangeSummary builds a [ResourceChangeSummary] from the
+// individual resource changes that were part of a step's plan, for the
+// runner to assign to [StepResult.ResourceChanges] once the plan (or apply)
+// has produced its final set of changes.
+//
+// It returns nil for a step with no changes at all, so that reporters can
+// treat a nil ResourceChanges field as "nothing to report" without needing
+// to separately check for an empty one.
+func BuildResourceChangeSummary(changes []ResourceChange) *ResourceChangeSummary {
+	if len(changes) == 0 {
+		return nil
+	}
+	summary := &ResourceChangeSummary{
+		ActionCounts: make(map[plans.Action]int, len(changes)),
+		Changes:      changes,
+	}
+	for _, change := range changes {
+		summary.ActionCounts[change.Action]++
+	}
+	return summary
+}
+
+// DiagnosticExpectation describes one "expect_diagnostics" block declared
+// for a test step, giving the criteria that an actual diagnostic must meet
+// in order to satisfy it.
+//
+// SummaryMatches and DetailMatches are both optional; a nil pattern is
+// treated as matching anything. SourceRange is also optional and, when set,
+// requires the diagnostic's own source range to fall within it; this is
+// mainly useful for distinguishing between two diagnostics with similar
+// messages that originate from different parts of the configuration.
+type DiagnosticExpectation struct {
+	Severity       tfdiags.Severity
+	SummaryMatches *regexp.Regexp
+	DetailMatches  *regexp.Regexp
+	SourceRange    *tfdiags.SourceRange
+}
+
+// Matches reports whether the given diagnostic satisfies this expectation.
+func (e DiagnosticExpectation) Matches(diag tfdiags.Diagnostic) bool {
+	if diag.Severity() != e.Severity {
+		return false
+	}
+	desc := diag.Description()
+	if e.SummaryMatches != nil && !e.SummaryMatches.MatchString(desc.Summary) {
+		return false
+	}
+	if e.DetailMatches != nil && !e.DetailMatches.MatchString(desc.Detail) {
+		return false
+	}
+	if e.SourceRange != nil {
+		subject := diag.Source().Subject
+		if subject == nil || !sourceRangeContains(*e.SourceRange, *subject) {
+			return false
+		}
+	}
+	return true
+}
+
+// sourceRangeContains reports whether inner falls entirely within outer:
+// the same file, with inner's start no earlier than outer's start and
+// inner's end no later than outer's end, measured in byte offsets.
+func sourceRangeContains(outer, inner tfdiags.SourceRange) bool {
+	if outer.Filename != inner.Filename {
+		return false
+	}
+	return inner.Start.Byte >= outer.Start.Byte && inner.End.Byte <= outer.End.Byte
+}
+
+// ExpectedDiagnosticResult pairs a [DiagnosticExpectation] with the outcome
+// of trying to match it against a step's diagnostics.
+type ExpectedDiagnosticResult struct {
+	Expectation DiagnosticExpectation
+
+	// Satisfied is true if some diagnostic in the step matched this
+	// expectation. Each diagnostic can only satisfy one expectation, so
+	// two identical "expect_diagnostics" blocks require two matching
+	// diagnostics to both be satisfied.
+	Satisfied bool
+}
+
+// MatchExpectedDiagnostics matches diags against expectations, consuming at
+// most one diagnostic per expectation, and reports both the diagnostics left
+// unmatched and the outcome of every expectation.
+//
+// This finds a maximum bipartite matching between expectations and diags,
+// rather than greedily assigning each expectation the first diagnostic that
+// satisfies it: a greedy assignment can report an expectation as
+// unsatisfied even when some other complete assignment exists, if an
+// earlier expectation happened to claim the one diagnostic that only it
+// could have matched.
+//
+// The returned remaining diagnostics preserve the order of diags. The
+// returned results are in the same order as expectations.
+func MatchExpectedDiagnostics(diags tfdiags.Diagnostics, expectations []DiagnosticExpectation) (remaining tfdiags.Diagnostics, results []ExpectedDiagnosticResult) {
+	// matchedDiag[i] is the index into expectations that diags[i] is
+	// assigned to, or -1 if it's unassigned.
+	matchedDiag := make([]int, len(diags))
+	for i := range matchedDiag {
+		matchedDiag[i] = -1
+	}
+	// matchedExpectation[i] is the index into diags that expectations[i] is
+	// assigned to, or -1 if it's unsatisfied.
+	matchedExpectation := make([]int, len(expectations))
+	for i := range matchedExpectation {
+		matchedExpectation[i] = -1
+	}
+
+	// augment tries to find an augmenting path starting from expectation
+	// expIdx, using Kuhn's algorithm: either some unclaimed diagnostic
+	// satisfies it directly, or some diagnostic already claimed by another
+	// expectation can be freed up by reassigning that expectation to a
+	// different diagnostic instead.
+	var augment func(expIdx int, visited []bool) bool
+	augment = func(expIdx int, visited []bool) bool {
+		for diagIdx, diag := range diags {
+			if visited[diagIdx] || !expectations[expIdx].Matches(diag) {
+				continue
+			}
+			visited[diagIdx] = true
+			if matchedDiag[diagIdx] == -1 || augment(matchedDiag[diagIdx], visited) {
+				matchedDiag[diagIdx] = expIdx
+				matchedExpectation[expIdx] = diagIdx
+				return true
+			}
+		}
+		return false
+	}
+
+	for i := range expectations {
+		augment(i, make([]bool, len(diags)))
+	}
+
+	results = make([]ExpectedDiagnosticResult, len(expectations))
+	for i, expectation := range expectations {
+		results[i] = ExpectedDiagnosticResult{
+			Expectation: expectation,
+			Satisfied:   matchedExpectation[i] != -1,
+		}
+	}
+
+	for i, diag := range diags {
+		if matchedDiag[i] == -1 {
+			remaining = append(remaining, diag)
+		}
+	}
+	return remaining, results
+}
+
+// ApplyExpectedDiagnostics matches sr.Diagnostics against expectations,
+// recording the outcome in sr.ExpectedDiagnostics, and folds the result
+// into sr.Status: diagnostics that matched an expectation no longer count
+// against the step, but any expectation left unsatisfied promotes the step
+// to [checks.StatusFail], and any diagnostic left unmatched that's an error
+// promotes it to [checks.StatusError], consistent with the rules documented
+// on [StepResult.Status].
+//
+// sr.Diagnostics itself is left unmodified, since UI code is still expected
+// to be able to see every diagnostic the step produced; only sr.Status and
+// sr.ExpectedDiagnostics are updated here. sr.Status is only ever raised,
+// never lowered: a [checks.StatusError] already set on entry is kept even
+// if every error diagnostic matched an expectation.
+//
+// The caller is responsible for building expectations from the step's
+// "expect_diagnostics" blocks and calling this once the step's diagnostics
+// are final; that config-parsing step doesn't exist in this package yet.
+func (sr *StepResult) ApplyExpectedDiagnostics(expectations []DiagnosticExpectation) {
+	remaining, results := MatchExpectedDiagnostics(sr.Diagnostics, expectations)
+	sr.ExpectedDiagnostics = results
+
+	unsatisfied := false
+	for _, result := range results {
+		if !result.Satisfied {
+			unsatisfied = true
+			break
+		}
+	}
+
+	unmatchedError := false
+	for _, diag := range remaining {
+		if diag.Severity() == tfdiags.Error {
+			unmatchedError = true
+			break
+		}
+	}
+
+	switch {
+	case unmatchedError:
+		sr.Status = checks.StatusError
+	case unsatisfied && sr.Status != checks.StatusError:
+		sr.Status = checks.StatusFail
+	}
 }
 
 func (sr *StepResult) IsImplied() bool {
@@ -100,4 +398,4 @@ func (sr *StepResult) IsImplied() bool {
 		return true
 	}
 	return sr.Name[0] == '<'
-}
\ No newline at end of file
+}
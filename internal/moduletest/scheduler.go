@@ -0,0 +1,199 @@
+package moduletest
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/terraform/internal/checks"
+)
+
+// Scenario is the runtime representation of a single test scenario that the
+// [Scheduler] knows how to execute, independent of however the scenario's
+// steps are actually implemented.
+//
+// Index is the scenario's position in the configuration, and is what the
+// scheduler uses to restore a deterministic order to results once execution
+// has finished, regardless of what order the scenarios actually ran in.
+type Scenario struct {
+	Index int
+	Name  string
+
+	// Parallel is true if the test author marked this scenario as eligible
+	// to run concurrently with other parallel scenarios, via the
+	// scenario-level "parallel" attribute. Scenarios that don't set this
+	// always run sequentially, both with respect to each other and to any
+	// parallel scenarios.
+	//
+	// NOTE: nothing in this package currently sets this field from actual
+	// test configuration, nor exposes Scheduler.Parallelism as a
+	// "-parallelism" CLI flag; both of those are config/command-layer
+	// concerns that don't exist yet. Scenario.Parallel and
+	// Scheduler.Parallelism must be populated directly by the caller in the
+	// meantime.
+	Parallel bool
+
+	// SharedResources identifies the backends, workspaces, or other
+	// external resources that this scenario reads or writes, so that the
+	// scheduler can avoid running two scenarios that touch the same
+	// resource at the same time even when both are marked Parallel.
+	SharedResources []string
+
+	// Run executes the scenario to completion (or until ctx is cancelled)
+	// and returns its result.
+	Run func(ctx context.Context) ScenarioResult
+}
+
+// Scheduler runs a set of scenarios, some of which may be marked parallel,
+// with at most Parallelism of them running at once, and returns their
+// results in the stable order implied by each scenario's Index.
+//
+// This is a bounded pool, not a work-stealing scheduler: there's no queue
+// that idle workers pull from. Instead, every parallel scenario gets its
+// own goroutine as soon as it's reached, and a channel-based semaphore caps
+// how many of those goroutines may be inside Scenario.Run at once. A
+// scenario whose SharedResources conflict with one already running blocks
+// only itself, inside its own goroutine, until the conflicting resource is
+// released; it never blocks the launch of other, unrelated scenarios.
+//
+// Scenarios that share an entry in SharedResources are never run
+// concurrently with one another, even if both are marked Parallel, since
+// doing so could corrupt shared backend or workspace state.
+type Scheduler struct {
+	// Parallelism is the maximum number of scenarios to run at once. A
+	// value of zero or less is treated as 1, i.e. fully sequential.
+	Parallelism int
+
+	// FailFast, if true, cancels the context passed to any scenario that
+	// hasn't started yet as soon as one scenario completes with
+	// [checks.StatusFail] or [checks.StatusError]. Scenarios already in
+	// progress are allowed to finish, but any scenario that does observe
+	// the cancellation partway through should report the steps it didn't
+	// reach as [checks.StatusUnknown], consistent with how a single
+	// scenario handles an earlier step failing.
+	FailFast bool
+}
+
+// Run executes all of the given scenarios according to the scheduler's
+// configuration and returns their results ordered by Scenario.Index.
+func (s *Scheduler) Run(ctx context.Context, scenarios []Scenario) []ScenarioResult {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	parallelism := s.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	results := make([]ScenarioResult, len(scenarios))
+	var mu sync.Mutex
+	cond := sync.NewCond(&mu)
+	resourcesInUse := make(map[string]bool)
+
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+
+	// claim blocks the calling goroutine, and only the calling goroutine,
+	// until either sc's SharedResources are all free (in which case it
+	// claims them and returns true) or ctx is cancelled (in which case it
+	// gives up and returns false). Scenarios with no conflicting resources
+	// in use never block here at all.
+	claim := func(sc Scenario) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		for {
+			select {
+			case <-ctx.Done():
+				return false
+			default:
+			}
+
+			conflict := false
+			for _, r := range sc.SharedResources {
+				if resourcesInUse[r] {
+					conflict = true
+					break
+				}
+			}
+			if !conflict {
+				for _, r := range sc.SharedResources {
+					resourcesInUse[r] = true
+				}
+				return true
+			}
+			cond.Wait()
+		}
+	}
+
+	release := func(sc Scenario) {
+		mu.Lock()
+		for _, r := range sc.SharedResources {
+			resourcesInUse[r] = false
+		}
+		mu.Unlock()
+		cond.Broadcast()
+	}
+
+	runOne := func(sc Scenario) {
+		defer wg.Done()
+
+		if !claim(sc) {
+			results[sc.Index] = ScenarioResult{Name: sc.Name, Status: checks.StatusUnknown}
+			return
+		}
+		defer release(sc)
+
+		sem <- struct{}{}
+		defer func() { <-sem }()
+
+		select {
+		case <-ctx.Done():
+			results[sc.Index] = ScenarioResult{Name: sc.Name, Status: checks.StatusUnknown}
+			return
+		default:
+		}
+
+		result := sc.Run(ctx)
+		results[sc.Index] = result
+
+		if s.FailFast && (result.Status == checks.StatusFail || result.Status == checks.StatusError) {
+			cancel()
+			// Wake any goroutine parked in claim so it notices ctx is done
+			// instead of waiting indefinitely for a resource that will
+			// never be released by a scenario that's never going to start.
+			cond.Broadcast()
+		}
+	}
+
+	// Scenarios not marked Parallel run strictly in Index order, each one
+	// acting as a barrier: no later scenario, parallel or not, may start
+	// until it has finished. Parallel scenarios in between two such
+	// barriers get their own goroutine immediately and run concurrently
+	// with each other and with the rest of the pool, subject only to their
+	// own SharedResources conflicts and the Parallelism cap.
+	ordered := append([]Scenario(nil), scenarios...)
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].Index < ordered[j].Index })
+
+	for _, sc := range ordered {
+		select {
+		case <-ctx.Done():
+			results[sc.Index] = ScenarioResult{Name: sc.Name, Status: checks.StatusUnknown}
+			continue
+		default:
+		}
+
+		if !sc.Parallel {
+			wg.Wait() // let every scenario launched so far finish first
+			wg.Add(1)
+			go runOne(sc)
+			wg.Wait() // and don't let anything after it start until it's done
+			continue
+		}
+
+		wg.Add(1)
+		go runOne(sc)
+	}
+	wg.Wait()
+
+	return results
+}
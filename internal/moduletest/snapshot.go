@@ -0,0 +1,218 @@
+package moduletest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/terraform/internal/checks"
+)
+
+// SnapshotStatus describes the outcome of comparing a single "snapshot"
+// block's value against its golden file on disk.
+type SnapshotStatus rune
+
+const (
+	// SnapshotPass means the snapshot's value matched the golden file
+	// exactly.
+	SnapshotPass SnapshotStatus = 'P'
+
+	// SnapshotFail means the snapshot's value differed from the golden
+	// file, and the run was not in --update-snapshots mode. It's also used,
+	// in either mode, when the golden file couldn't be read or written.
+	SnapshotFail SnapshotStatus = 'F'
+
+	// SnapshotMissing means there was no golden file yet for this
+	// snapshot. Like SnapshotFail, this fails the step unless the run is in
+	// --update-snapshots mode, since an absent golden file almost always
+	// means the test author forgot to commit one rather than that they
+	// intended an empty baseline.
+	SnapshotMissing SnapshotStatus = 'M'
+
+	// SnapshotUpdated means the golden file didn't match (or didn't exist)
+	// but the run was in --update-snapshots mode, so the runner rewrote it
+	// to match the current value instead of failing the step.
+	SnapshotUpdated SnapshotStatus = 'U'
+)
+
+// SnapshotResult is the outcome of evaluating one "snapshot" block declared
+// in a test step.
+type SnapshotResult struct {
+	Status SnapshotStatus
+
+	// Diff is a unified diff between the golden file's previous contents
+	// and the value produced by this run, for reporters to show the author
+	// what changed. It's empty when Status is [SnapshotPass], and it
+	// describes the new-file creation when Status is [SnapshotMissing] or
+	// [SnapshotUpdated] with no prior golden file. When Status is
+	// [SnapshotFail] because the golden file couldn't be read or written,
+	// Diff holds a description of that error instead of a diff.
+	Diff string
+}
+
+// Snapshots, when populated on a [StepResult], maps each "snapshot" block's
+// declared name to the result of comparing it against its golden file.
+//
+// Snapshot names only need to be unique within the step that declares them,
+// so this is keyed directly by name rather than by one of the addrs package's
+// checkable address types.
+type Snapshots map[string]SnapshotResult
+
+// AnyMismatch reports whether any of the snapshots in this result set would
+// require the step to be treated as failing, i.e. whether any are
+// [SnapshotFail] or [SnapshotMissing]. A set where --update-snapshots
+// rewrote every mismatch, leaving only [SnapshotPass] and [SnapshotUpdated]
+// entries, does not count as a mismatch.
+func (s Snapshots) AnyMismatch() bool {
+	for _, result := range s {
+		switch result.Status {
+		case SnapshotFail, SnapshotMissing:
+			return true
+		}
+	}
+	return false
+}
+
+// CompareSnapshot evaluates a single "snapshot" block by comparing value,
+// the current rendering of its "value" expression, against the golden file
+// at goldenPath.
+//
+// If update is true (--update-snapshots mode) then a mismatch or a missing
+// golden file is rewritten to match value rather than reported as a
+// failure, yielding [SnapshotUpdated] instead of [SnapshotFail] or
+// [SnapshotMissing].
+func CompareSnapshot(goldenPath, value string, update bool) SnapshotResult {
+	existing, err := os.ReadFile(goldenPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if !update {
+			return SnapshotResult{
+				Status: SnapshotMissing,
+				Diff:   unifiedDiff(goldenPath, "", value),
+			}
+		}
+		if werr := os.WriteFile(goldenPath, []byte(value), 0o644); werr != nil {
+			return SnapshotResult{
+				Status: SnapshotFail,
+				Diff:   fmt.Sprintf("failed to create golden file %s: %s", goldenPath, werr),
+			}
+		}
+		return SnapshotResult{Status: SnapshotUpdated, Diff: unifiedDiff(goldenPath, "", value)}
+
+	case err != nil:
+		return SnapshotResult{
+			Status: SnapshotFail,
+			Diff:   fmt.Sprintf("failed to read golden file %s: %s", goldenPath, err),
+		}
+	}
+
+	if string(existing) == value {
+		return SnapshotResult{Status: SnapshotPass}
+	}
+
+	diff := unifiedDiff(goldenPath, string(existing), value)
+	if !update {
+		return SnapshotResult{Status: SnapshotFail, Diff: diff}
+	}
+	if werr := os.WriteFile(goldenPath, []byte(value), 0o644); werr != nil {
+		return SnapshotResult{
+			Status: SnapshotFail,
+			Diff:   fmt.Sprintf("failed to update golden file %s: %s", goldenPath, werr),
+		}
+	}
+	return SnapshotResult{Status: SnapshotUpdated, Diff: diff}
+}
+
+// ApplySnapshots records the outcome of comparing a step's snapshot blocks
+// against their golden files and folds any mismatch into sr.Status, the
+// same way a failing check does: a mismatch takes the step down to
+// [checks.StatusFail] unless it was already at [checks.StatusError].
+//
+// The caller is expected to have already evaluated each "snapshot" block's
+// "value" expression and run it through [CompareSnapshot] to build
+// snapshots; that, and the "--update-snapshots" command-line flag it reads
+// its update argument from, belong to the config and command layers, which
+// don't exist in this package.
+func (sr *StepResult) ApplySnapshots(snapshots Snapshots) {
+	sr.Snapshots = snapshots
+	if snapshots.AnyMismatch() && sr.Status != checks.StatusError {
+		sr.Status = checks.StatusFail
+	}
+}
+
+// unifiedDiff renders a minimal unified diff between before and after,
+// labeling both sides with path since that's the only name either one has
+// (the golden file doesn't have a separate "new" filename to report).
+func unifiedDiff(path, before, after string) string {
+	beforeLines := strings.Split(before, "\n")
+	afterLines := strings.Split(after, "\n")
+	common := longestCommonSubsequence(beforeLines, afterLines)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s (golden)\n+++ %s (current)\n", path, path)
+
+	bi, ai, ci := 0, 0, 0
+	for ci < len(common) {
+		for bi < len(beforeLines) && beforeLines[bi] != common[ci] {
+			fmt.Fprintf(&b, "-%s\n", beforeLines[bi])
+			bi++
+		}
+		for ai < len(afterLines) && afterLines[ai] != common[ci] {
+			fmt.Fprintf(&b, "+%s\n", afterLines[ai])
+			ai++
+		}
+		fmt.Fprintf(&b, " %s\n", common[ci])
+		bi++
+		ai++
+		ci++
+	}
+	for ; bi < len(beforeLines); bi++ {
+		fmt.Fprintf(&b, "-%s\n", beforeLines[bi])
+	}
+	for ; ai < len(afterLines); ai++ {
+		fmt.Fprintf(&b, "+%s\n", afterLines[ai])
+	}
+	return b.String()
+}
+
+// longestCommonSubsequence returns the longest common subsequence of a and
+// b, computed by straightforward dynamic programming. This is the textbook
+// approach used to build a unified diff and is fine for the
+// human-scale snapshot values this is meant for; it isn't intended to
+// scale to huge files the way a real diff tool would.
+func longestCommonSubsequence(a, b []string) []string {
+	n, m := len(a), len(b)
+	length := make([][]int, n+1)
+	for i := range length {
+		length[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				length[i][j] = length[i+1][j+1] + 1
+			case length[i+1][j] >= length[i][j+1]:
+				length[i][j] = length[i+1][j]
+			default:
+				length[i][j] = length[i][j+1]
+			}
+		}
+	}
+
+	var common []string
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			common = append(common, a[i])
+			i++
+			j++
+		case length[i+1][j] >= length[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return common
+}